@@ -0,0 +1,209 @@
+package catcherflusher
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+)
+
+// ErrBodyTooLarge is returned by [BodyBuffer.Write] once the captured body
+// has reached its configured hard limit. Any bytes beyond that limit are
+// dropped by the BodyBuffer, the caller decides what to do about it: reject
+// the request, or simply mark the capture as truncated and move on.
+var ErrBodyTooLarge = errors.New("catcherflusher: body exceeds max capture size")
+
+// BodyBuffer is an io.Writer that captures up to memLimit bytes in memory and,
+// beyond that, spills to a temporary file on disk until maxLimit is reached,
+// at which point further writes are rejected with [ErrBodyTooLarge]. It lets
+// callers capture request/response bodies for logging without trusting
+// Content-Length and without risking unbounded memory growth.
+//
+// The zero value is not usable, create one with [NewBodyBuffer]. A BodyBuffer
+// is not safe for concurrent use.
+type BodyBuffer struct {
+	memLimit  int64
+	maxLimit  int64
+	tempDir   string
+	mem       bytes.Buffer
+	file      *os.File
+	filePath  string
+	size      int64
+	truncated bool
+}
+
+// NewBodyBuffer returns a BodyBuffer that keeps up to memLimit bytes in
+// memory, spills anything beyond that to a temporary file created in
+// tempDir (os.TempDir() if empty), and refuses to capture past maxLimit
+// bytes in total.
+func NewBodyBuffer(memLimit, maxLimit int64, tempDir string) *BodyBuffer {
+	return &BodyBuffer{
+		memLimit: memLimit,
+		maxLimit: maxLimit,
+		tempDir:  tempDir,
+	}
+}
+
+// Write implements io.Writer. Once the total number of bytes written reaches
+// maxLimit, Write accepts no more data and returns [ErrBodyTooLarge] alongside
+// the (possibly partial) number of bytes it did accept, as mandated by the
+// io.Writer contract for short writes.
+func (b *BodyBuffer) Write(p []byte) (int, error) {
+	remaining := b.maxLimit - b.size
+	if remaining <= 0 {
+		b.truncated = true
+		return 0, ErrBodyTooLarge
+	}
+	toAccept := p
+	overflow := false
+	if int64(len(p)) > remaining {
+		toAccept = p[:remaining]
+		overflow = true
+	}
+	n, err := b.store(toAccept)
+	b.size += int64(n)
+	if err != nil {
+		return n, err
+	}
+	if overflow {
+		b.truncated = true
+		return n, ErrBodyTooLarge
+	}
+	return n, nil
+}
+
+// store writes p to memory until memLimit is reached, then spills to a
+// temporary file for this and any subsequent write.
+func (b *BodyBuffer) store(p []byte) (int, error) {
+	if b.file == nil && int64(b.mem.Len())+int64(len(p)) <= b.memLimit {
+		return b.mem.Write(p)
+	}
+	if b.file == nil {
+		f, err := os.CreateTemp(b.tempDir, "httplog-body-*.tmp")
+		if err != nil {
+			return 0, err
+		}
+		b.file = f
+		b.filePath = f.Name()
+		if b.mem.Len() > 0 {
+			if _, err := b.file.Write(b.mem.Bytes()); err != nil {
+				return 0, err
+			}
+			b.mem.Reset()
+		}
+	}
+	return b.file.Write(p)
+}
+
+// Size returns the total number of bytes accepted so far.
+func (b *BodyBuffer) Size() int64 {
+	return b.size
+}
+
+// Truncated reports whether some bytes were dropped because the capture hit
+// maxLimit.
+func (b *BodyBuffer) Truncated() bool {
+	return b.truncated
+}
+
+// Peek returns up to n bytes from the start of the captured body, without
+// consuming it. It is meant for building a bounded log excerpt without
+// loading an entire disk-spilled body into memory.
+func (b *BodyBuffer) Peek(n int64) ([]byte, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	if b.file == nil {
+		data := b.mem.Bytes()
+		if int64(len(data)) > n {
+			data = data[:n]
+		}
+		return data, nil
+	}
+	f, err := os.Open(b.filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	buf := make([]byte, n)
+	read, err := io.ReadFull(f, buf)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return buf[:read], nil
+}
+
+// Reader returns a fresh, independent reader over the whole captured body,
+// for handing the original bytes back to a downstream consumer (e.g.
+// restoring http.Request.Body after capturing it). Closing the returned
+// reader does not affect the BodyBuffer itself.
+func (b *BodyBuffer) Reader() (io.ReadCloser, error) {
+	if b.file == nil {
+		return io.NopCloser(bytes.NewReader(b.mem.Bytes())), nil
+	}
+	f, err := os.Open(b.filePath)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Close releases the temporary file backing this BodyBuffer, if any. It is
+// safe to call Close multiple times, and on a BodyBuffer that never spilled
+// to disk. Callers should always defer Close once a BodyBuffer is created, so
+// the temp file does not outlive the request, including when the wrapped
+// handler panics.
+func (b *BodyBuffer) Close() error {
+	if b.file == nil {
+		return nil
+	}
+	err := b.file.Close()
+	if rmErr := os.Remove(b.filePath); err == nil {
+		err = rmErr
+	}
+	b.file = nil
+	return err
+}
+
+// quietWriter adapts a BodyBuffer so [io.TeeReader] never sees
+// [ErrBodyTooLarge]: bytes beyond the hard limit are reported as accepted
+// (and silently dropped) instead of failing the write, so capturing past the
+// limit truncates the capture without ever failing the read it is tee'd
+// from.
+type quietWriter struct {
+	w io.Writer
+}
+
+func (q quietWriter) Write(p []byte) (int, error) {
+	n, err := q.w.Write(p)
+	if errors.Is(err, ErrBodyTooLarge) {
+		return len(p), nil
+	}
+	return n, err
+}
+
+// captureReader pairs the [io.TeeReader] built by [NewCaptureReader] with the
+// original io.Closer, since io.TeeReader only implements io.Reader.
+type captureReader struct {
+	io.Reader
+	closer io.Closer
+}
+
+func (c captureReader) Close() error {
+	return c.closer.Close()
+}
+
+// NewCaptureReader wraps rc so every byte read through it is also written to
+// buf, up to buf's configured limits. Capture is a side channel: once buf
+// hits its hard limit (buf.Truncated() becomes true), further bytes are
+// simply not captured, they still reach the caller exactly as read from rc.
+// This lets a Logger capture a request body for logging without the size
+// limit ever affecting what the wrapped handler actually reads. Any other
+// error from buf (e.g. failing to create its temporary file) is returned by
+// Read. Close forwards to rc.
+func NewCaptureReader(rc io.ReadCloser, buf *BodyBuffer) io.ReadCloser {
+	return captureReader{
+		Reader: io.TeeReader(rc, quietWriter{buf}),
+		closer: rc,
+	}
+}
@@ -0,0 +1,132 @@
+package catcherflusher
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestBodyBufferMemoryOnly(t *testing.T) {
+	b := NewBodyBuffer(1024, 1024, "")
+	defer b.Close()
+
+	data := []byte("hello world")
+	if _, err := b.Write(data); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if got := b.Size(); got != int64(len(data)) {
+		t.Fatalf("Size() = %d, want %d", got, len(data))
+	}
+	if b.Truncated() {
+		t.Fatal("Truncated() = true, want false")
+	}
+	peeked, err := b.Peek(int64(len(data)))
+	if err != nil {
+		t.Fatalf("Peek: %v", err)
+	}
+	if string(peeked) != string(data) {
+		t.Fatalf("Peek() = %q, want %q", peeked, data)
+	}
+	rc, err := b.Reader()
+	if err != nil {
+		t.Fatalf("Reader: %v", err)
+	}
+	defer rc.Close()
+	read, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if string(read) != string(data) {
+		t.Fatalf("Reader() content = %q, want %q", read, data)
+	}
+}
+
+func TestBodyBufferSpillsToDisk(t *testing.T) {
+	b := NewBodyBuffer(4, 1024, t.TempDir())
+	defer b.Close()
+
+	data := []byte("this is longer than the memory limit")
+	if _, err := b.Write(data); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if b.file == nil {
+		t.Fatal("expected BodyBuffer to have spilled to a temporary file")
+	}
+	rc, err := b.Reader()
+	if err != nil {
+		t.Fatalf("Reader: %v", err)
+	}
+	defer rc.Close()
+	read, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if string(read) != string(data) {
+		t.Fatalf("spilled content = %q, want %q", read, data)
+	}
+}
+
+func TestBodyBufferTruncatesAtMaxLimit(t *testing.T) {
+	b := NewBodyBuffer(1024, 5, "")
+	defer b.Close()
+
+	n, err := b.Write([]byte("0123456789"))
+	if !errors.Is(err, ErrBodyTooLarge) {
+		t.Fatalf("Write error = %v, want ErrBodyTooLarge", err)
+	}
+	if n != 5 {
+		t.Fatalf("Write accepted %d bytes, want 5", n)
+	}
+	if !b.Truncated() {
+		t.Fatal("Truncated() = false, want true")
+	}
+	if got := b.Size(); got != 5 {
+		t.Fatalf("Size() = %d, want 5", got)
+	}
+	if _, err := b.Write([]byte("more")); !errors.Is(err, ErrBodyTooLarge) {
+		t.Fatalf("Write past limit error = %v, want ErrBodyTooLarge", err)
+	}
+}
+
+func TestNewCaptureReaderDoesNotFailOnOversizedBody(t *testing.T) {
+	const full = "0123456789ABCDEF"
+	buf := NewBodyBuffer(1024, 4, "")
+	defer buf.Close()
+
+	cr := NewCaptureReader(io.NopCloser(strings.NewReader(full)), buf)
+	got, err := io.ReadAll(cr)
+	if err != nil {
+		t.Fatalf("ReadAll through NewCaptureReader: %v", err)
+	}
+	if string(got) != full {
+		t.Fatalf("reader returned %q, want the full original body %q", got, full)
+	}
+	if !buf.Truncated() {
+		t.Fatal("Truncated() = false, want true once capture exceeds MaxBodyBytes")
+	}
+	if buf.Size() != 4 {
+		t.Fatalf("captured Size() = %d, want 4 (the configured cap)", buf.Size())
+	}
+	if err := cr.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
+
+func TestNewCaptureReaderUnderLimitCapturesEverything(t *testing.T) {
+	const body = "small body"
+	buf := NewBodyBuffer(1024, 1024, "")
+	defer buf.Close()
+
+	cr := NewCaptureReader(io.NopCloser(bytes.NewBufferString(body)), buf)
+	if _, err := io.ReadAll(cr); err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if buf.Truncated() {
+		t.Fatal("Truncated() = true, want false")
+	}
+	if buf.Size() != int64(len(body)) {
+		t.Fatalf("Size() = %d, want %d", buf.Size(), len(body))
+	}
+}
@@ -0,0 +1,7 @@
+package catcherflusher
+
+// ShouldLogBodyFunc decides, given the final HTTP response status code,
+// whether a captured request/response body should actually be emitted to the
+// log. It lets callers capture bodies cheaply in production and only pay the
+// cost of logging them when something went wrong.
+type ShouldLogBodyFunc func(status int) bool
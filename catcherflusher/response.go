@@ -0,0 +1,454 @@
+package catcherflusher
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// StreamingContentTypes is the default list of content types considered to
+// be streaming. If a ResponseWriter's response Content-Type is in this list,
+// WriteHeader and any subsequent writes will be flushed directly to the
+// client. It is used by [NewResponseWriter] as the ResponseWriter's list
+// whenever Config.StreamingContentTypes is nil.
+var StreamingContentTypes = []string{
+	"text/event-stream",
+	"application/x-ndjson",
+}
+
+// Config bundles the tunables used to build a [ResponseWriter]: its body
+// capture limits and the Content-Type values that trigger streaming
+// auto-flush.
+type Config struct {
+	MemBodyBytes, MaxBodyBytes int64
+	BodyTempDir                string
+	// StreamingContentTypes overrides [StreamingContentTypes] for this
+	// ResponseWriter. A nil slice falls back to the package default.
+	StreamingContentTypes []string
+	// StartTime anchors [ResponseWriter.GetTTFB]: it should be the time the
+	// request was received, not the time the ResponseWriter was built, so
+	// that time spent reading/buffering the request body beforehand counts
+	// towards time-to-first-byte. A zero value falls back to time.Now().
+	StartTime time.Time
+}
+
+// NewResponseWriter returns a new ResponseWriter that wraps the provided
+// http.ResponseWriter. It will capture the HTTP response code and also capture
+// all writes if captureBody is set to true, within a [BodyBuffer] built from
+// cfg.MemBodyBytes, cfg.MaxBodyBytes and cfg.BodyTempDir (see
+// [NewBodyBuffer]). Callers that set captureBody must defer a call to
+// [ResponseWriter.Close] so any temporary file backing the capture is
+// removed.
+func NewResponseWriter(w http.ResponseWriter, captureBody bool, cfg Config) (rw *ResponseWriter) {
+	if w == nil {
+		return
+	}
+	streamingContentTypes := cfg.StreamingContentTypes
+	if streamingContentTypes == nil {
+		streamingContentTypes = StreamingContentTypes
+	}
+	startTime := cfg.StartTime
+	if startTime.IsZero() {
+		startTime = time.Now()
+	}
+	rw = &ResponseWriter{
+		wrapped:               w,
+		startTime:             startTime,
+		streamingContentTypes: streamingContentTypes,
+	}
+	if f, ok := w.(http.Flusher); ok {
+		rw.flusher = f
+	}
+	if h, ok := w.(http.Hijacker); ok {
+		rw.hijacker = h
+	}
+	if p, ok := w.(http.Pusher); ok {
+		rw.pusher = p
+	}
+	if cn, ok := w.(http.CloseNotifier); ok { //nolint:staticcheck // passthrough, not a new usage
+		rw.closeNotifier = cn
+	}
+	if rf, ok := w.(io.ReaderFrom); ok {
+		rw.readerFrom = rf
+	}
+	if captureBody {
+		rw.body = NewBodyBuffer(cfg.MemBodyBytes, cfg.MaxBodyBytes, cfg.BodyTempDir)
+	}
+	return
+}
+
+// ResponseWriter is a wrapper around http.ResponseWriter that captures the
+// response code and body. It also provides auto-flushing of the underlying
+// writer if the content type is in its configured streaming content types
+// (see [Config.StreamingContentTypes]).
+type ResponseWriter struct {
+	wrapped               http.ResponseWriter
+	flusher               http.Flusher
+	hijacker              http.Hijacker
+	pusher                http.Pusher
+	closeNotifier         http.CloseNotifier //nolint:staticcheck // passthrough, not a new usage
+	readerFrom            io.ReaderFrom
+	code                  int
+	body                  *BodyBuffer
+	hijacked              bool
+	bytesWritten          int64
+	writeErr              error
+	writeErrCount         int
+	startTime             time.Time
+	ttfb                  time.Duration
+	ttfbSet               bool
+	headerBytes           int
+	streamingContentTypes []string
+}
+
+// GetResponseCode returns the HTTP response code that was written to the
+// underlying http.ResponseWriter.
+func (rw *ResponseWriter) GetResponseCode() int {
+	return rw.code
+}
+
+// GetBodySize returns the number of bytes captured from the body that was
+// written to the underlying http.ResponseWriter. Note that captureBody must
+// be set to true when creating the ResponseWriter for the body to be
+// captured.
+func (rw *ResponseWriter) GetBodySize() int64 {
+	if rw.body == nil {
+		return 0
+	}
+	return rw.body.Size()
+}
+
+// PeekBody returns up to n bytes from the start of the captured body, without
+// loading the whole of it in memory if it was spilled to disk.
+func (rw *ResponseWriter) PeekBody(n int64) ([]byte, error) {
+	if rw.body == nil {
+		return nil, nil
+	}
+	return rw.body.Peek(n)
+}
+
+// GetBodyTruncated reports whether some response body bytes were dropped
+// because the capture hit its hard size limit (maxBodyBytes).
+func (rw *ResponseWriter) GetBodyTruncated() bool {
+	return rw.body != nil && rw.body.Truncated()
+}
+
+// Close releases the temporary file backing the captured body, if any. It is
+// a no-op if captureBody was false when creating the ResponseWriter. Callers
+// must defer a call to Close once a ResponseWriter is created with
+// captureBody set, including when the wrapped handler panics.
+func (rw *ResponseWriter) Close() error {
+	if rw.body == nil {
+		return nil
+	}
+	return rw.body.Close()
+}
+
+// GetWriteError returns the first error returned by the wrapped
+// http.ResponseWriter's Write method, if any. Handlers routinely ignore this
+// error (http.ResponseWriter.Write's contract does not require them not to),
+// so this is the only way to learn a response was cut short.
+func (rw *ResponseWriter) GetWriteError() error {
+	return rw.writeErr
+}
+
+// GetWriteErrorCount returns how many calls to the wrapped
+// http.ResponseWriter's Write method returned an error.
+func (rw *ResponseWriter) GetWriteErrorCount() int {
+	return rw.writeErrCount
+}
+
+// WriteErrorSummary returns a human readable summary of the first write
+// error encountered, in the form "response write failed after N bytes: err",
+// or the empty string if no write ever failed.
+func (rw *ResponseWriter) WriteErrorSummary() string {
+	if rw.writeErr == nil {
+		return ""
+	}
+	return fmt.Sprintf("response write failed after %d bytes: %s", rw.bytesWritten, rw.writeErr)
+}
+
+// GetTTFB returns the Time To First Byte: the delay between cfg.StartTime
+// (as passed to [NewResponseWriter]) and the first call to Write or
+// WriteHeader that sent a final (2xx-5xx) status code. It is zero if no such
+// call happened yet.
+func (rw *ResponseWriter) GetTTFB() time.Duration {
+	return rw.ttfb
+}
+
+// GetBytesWritten returns the number of response body bytes successfully
+// written to the wrapped http.ResponseWriter, whether via
+// [ResponseWriter.Write] or the [io.ReaderFrom] fast-path in
+// [ResponseWriter.ReadFrom].
+func (rw *ResponseWriter) GetBytesWritten() int64 {
+	return rw.bytesWritten
+}
+
+// GetHeaderBytes returns an approximation of the size, in bytes, of the
+// response header block sent to the client (field names and values only,
+// not counting the status line). It is zero until a final status code has
+// been written.
+func (rw *ResponseWriter) GetHeaderBytes() int {
+	return rw.headerBytes
+}
+
+// GetHijacked reports whether the underlying connection was taken over via
+// [ResponseWriter.Hijack]. Once hijacked, the response code and body captured
+// by this ResponseWriter are meaningless: the wrapped handler is talking
+// directly to the raw connection.
+func (rw *ResponseWriter) GetHijacked() bool {
+	return rw.hijacked
+}
+
+/*
+	Implements http.ResponseWriter
+*/
+
+// Header returns the header map that will be sent by
+// [ResponseWriter.WriteHeader]. The [Header] map also is the mechanism with which
+// [Handler] implementations can set HTTP trailers.
+//
+// Changing the header map after a call to [ResponseWriter.WriteHeader] (or
+// [ResponseWriter.Write]) has no effect unless the HTTP status code was of the
+// 1xx class or the modified headers are trailers.
+//
+// There are two ways to set Trailers. The preferred way is to
+// predeclare in the headers which trailers you will later
+// send by setting the "Trailer" header to the names of the
+// trailer keys which will come later. In this case, those
+// keys of the Header map are treated as if they were
+// trailers. See the example. The second way, for trailer
+// keys not known to the [Handler] until after the first [ResponseWriter.Write],
+// is to prefix the [Header] map keys with the [TrailerPrefix]
+// constant value.
+//
+// To suppress automatic response headers (such as "Date"), set
+// their value to nil.
+func (rw *ResponseWriter) Header() http.Header {
+	return rw.wrapped.Header()
+}
+
+// Write writes the data to the connection as part of an HTTP reply.
+//
+// If [ResponseWriter.WriteHeader] has not yet been called, Write calls
+// WriteHeader(http.StatusOK) before writing the data. If the Header
+// does not contain a Content-Type line, Write adds a Content-Type set
+// to the result of passing the initial 512 bytes of written data to
+// [DetectContentType]. Additionally, if the total size of all written
+// data is under a few KB and there are no Flush calls, the
+// Content-Length header is added automatically.
+//
+// Depending on the HTTP protocol version and the client, calling
+// Write or WriteHeader may prevent future reads on the
+// Request.Body. For HTTP/1.x requests, handlers should read any
+// needed request body data before writing the response. Once the
+// headers have been flushed (due to either an explicit Flusher.Flush
+// call or writing enough data to trigger a flush), the request body
+// may be unavailable. For HTTP/2 requests, the Go HTTP server permits
+// handlers to continue to read the request body while concurrently
+// writing the response. However, such behavior may not be supported
+// by all HTTP/2 clients. Handlers should read before writing if
+// possible to maximize compatibility.
+func (rw *ResponseWriter) Write(data []byte) (int, error) {
+	if rw.code == 0 {
+		// wrapped write will do it itself on the wrapped response if we don't
+		// but we won't know about it: let's do it ourself.
+		rw.WriteHeader(http.StatusOK)
+	}
+	if rw.body != nil {
+		// ErrBodyTooLarge just means the capture got truncated, the actual
+		// response to the client below is unaffected.
+		if _, err := rw.body.Write(data); err != nil && !errors.Is(err, ErrBodyTooLarge) {
+			// drop the capture, but still remove any temp file it created
+			rw.body.Close()
+			rw.body = nil
+		}
+	}
+	if rw.flusher != nil {
+		defer rw.flusher.Flush()
+	}
+	n, err := rw.wrapped.Write(data)
+	rw.bytesWritten += int64(n)
+	if err != nil {
+		rw.writeErrCount++
+		if rw.writeErr == nil {
+			rw.writeErr = err
+		}
+	}
+	return n, err
+}
+
+// WriteHeader sends an HTTP response header with the provided
+// status code.
+//
+// If WriteHeader is not called explicitly, the first call to Write
+// will trigger an implicit WriteHeader(http.StatusOK).
+// Thus explicit calls to WriteHeader are mainly used to
+// send error codes or 1xx informational responses.
+//
+// The provided code must be a valid HTTP 1xx-5xx status code.
+// Any number of 1xx headers may be written, followed by at most
+// one 2xx-5xx header. 1xx headers are sent immediately, but 2xx-5xx
+// headers may be buffered. Use the Flusher interface to send
+// buffered data. The header map is cleared when 2xx-5xx headers are
+// sent, but not with 1xx headers.
+//
+// The server will automatically send a 100 (Continue) header
+// on the first read from the request body if the request has
+// an "Expect: 100-continue" header.
+//
+// If original ResponseWriter was an http.Flusher and current
+// content type is one of this ResponseWriter's configured streaming content
+// types (see [Config.StreamingContentTypes]), the header and any subsequent
+// Write will be flushed to the client and not buffered.
+func (rw *ResponseWriter) WriteHeader(statusCode int) {
+	// Forward and save the status code
+	rw.wrapped.WriteHeader(statusCode)
+	rw.code = statusCode
+	// 1xx headers are informational and may be followed by more headers:
+	// only a final status code marks the first byte of the actual response.
+	if !rw.ttfbSet && statusCode >= http.StatusOK {
+		rw.ttfb = time.Since(rw.startTime)
+		rw.ttfbSet = true
+		rw.headerBytes = headerBytesSize(rw.Header())
+	}
+	// Detect content types that are streaming and require flushing
+	if rw.flusher == nil {
+		// underlying response writer does not support flushing, abort
+		return
+	}
+	var shouldFlush bool
+	responseContentType := rw.Header().Get("Content-Type")
+	for _, ct := range rw.streamingContentTypes {
+		if ct == responseContentType {
+			shouldFlush = true
+			break
+		}
+	}
+	if shouldFlush {
+		defer rw.flusher.Flush()
+	} else {
+		// nullyfy flusher to prevent further flushes
+		rw.flusher = nil
+	}
+}
+
+/*
+	Implements http.Flusher
+*/
+
+// Flush sends any buffered data to the client if the underlying response
+// writer supports flushing. If not supported, this method does nothing.
+//
+// Note that flush is automatically called by Write and WriteHeader methods,
+// if the underlying response writer supports it (i.e., http.ResponseWriter)
+// and the content type of response is one of this ResponseWriter's configured
+// streaming content types (see [Config.StreamingContentTypes]).
+func (rw *ResponseWriter) Flush() {
+	if rw.flusher != nil {
+		rw.flusher.Flush()
+	}
+}
+
+/*
+	Implements http.Hijacker
+*/
+
+// ErrNotHijackable is returned by [ResponseWriter.Hijack] when the underlying
+// http.ResponseWriter does not implement http.Hijacker.
+var ErrNotHijackable = errors.New("catcherflusher: underlying ResponseWriter does not support hijacking")
+
+// Hijack lets the caller take over the connection, typically to upgrade it
+// (e.g. WebSockets). It is only supported if the wrapped http.ResponseWriter
+// supports it. On success, GetHijacked starts reporting true as the response
+// code and body captured so far are no longer meaningful.
+func (rw *ResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if rw.hijacker == nil {
+		return nil, nil, ErrNotHijackable
+	}
+	conn, buf, err := rw.hijacker.Hijack()
+	if err == nil {
+		rw.hijacked = true
+	}
+	return conn, buf, err
+}
+
+/*
+	Implements http.Pusher
+*/
+
+// Push initiates an HTTP/2 server push, forwarding to the wrapped
+// http.ResponseWriter. It returns [http.ErrNotSupported] if the underlying
+// http.ResponseWriter does not implement http.Pusher.
+func (rw *ResponseWriter) Push(target string, opts *http.PushOptions) error {
+	if rw.pusher == nil {
+		return http.ErrNotSupported
+	}
+	return rw.pusher.Push(target, opts)
+}
+
+/*
+	Implements http.CloseNotifier
+*/
+
+// CloseNotify returns a channel that receives a single value when the client
+// connection goes away, forwarding to the wrapped http.ResponseWriter. If the
+// underlying http.ResponseWriter does not implement http.CloseNotifier, a nil
+// channel is returned, which blocks forever on receive.
+//
+// Deprecated: use [http.Request.Context] instead, as documented on
+// [http.CloseNotifier]. Kept here only as a passthrough for handlers that
+// still rely on it.
+func (rw *ResponseWriter) CloseNotify() <-chan bool { //nolint:staticcheck // passthrough, not a new usage
+	if rw.closeNotifier == nil {
+		return nil
+	}
+	return rw.closeNotifier.CloseNotify()
+}
+
+/*
+	Implements io.ReaderFrom
+*/
+
+// onlyWriter hides any other method ResponseWriter implements, in particular
+// ReadFrom, so it can be safely handed to io.Copy as a plain io.Writer
+// fallback without re-entering ResponseWriter.ReadFrom.
+type onlyWriter struct {
+	io.Writer
+}
+
+// ReadFrom reads from r until EOF and writes the data to the underlying
+// http.ResponseWriter, forwarding to it directly if it implements
+// io.ReaderFrom (e.g. to benefit from a sendfile fast-path). Captured body
+// bytes and streaming auto-flush do not apply to data written through that
+// fast-path, as it never goes through [ResponseWriter.Write]; otherwise the
+// data is copied through [ResponseWriter.Write] and both apply.
+// [ResponseWriter.GetBytesWritten] is updated with however many bytes were
+// actually sent in either case.
+func (rw *ResponseWriter) ReadFrom(r io.Reader) (int64, error) {
+	if rw.code == 0 {
+		rw.WriteHeader(http.StatusOK)
+	}
+	if rw.readerFrom == nil {
+		return io.Copy(onlyWriter{rw}, r)
+	}
+	n, err := rw.readerFrom.ReadFrom(r)
+	rw.bytesWritten += n
+	return n, err
+}
+
+// headerBytesSize approximates the wire size of a header block: each
+// "name: value\r\n" line.
+func headerBytesSize(h http.Header) int {
+	var n int
+	for name, values := range h {
+		for _, v := range values {
+			n += len(name) + len(v) + 4 // ": " and "\r\n"
+		}
+	}
+	return n
+}
@@ -0,0 +1,171 @@
+package catcherflusher
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"io"
+	"testing"
+)
+
+func TestResponseWriterCapturesWriteAndCode(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rw := NewResponseWriter(rec, true, Config{MemBodyBytes: 1024, MaxBodyBytes: 1024})
+
+	rw.WriteHeader(http.StatusCreated)
+	n, err := rw.Write([]byte("hello"))
+	if err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if n != 5 {
+		t.Fatalf("Write returned %d, want 5", n)
+	}
+	if got := rw.GetResponseCode(); got != http.StatusCreated {
+		t.Fatalf("GetResponseCode() = %d, want %d", got, http.StatusCreated)
+	}
+	if got := rw.GetBytesWritten(); got != 5 {
+		t.Fatalf("GetBytesWritten() = %d, want 5", got)
+	}
+	peeked, err := rw.PeekBody(5)
+	if err != nil {
+		t.Fatalf("PeekBody: %v", err)
+	}
+	if string(peeked) != "hello" {
+		t.Fatalf("PeekBody() = %q, want %q", peeked, "hello")
+	}
+	if err := rw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
+
+func TestResponseWriterImplicitWriteHeaderOK(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rw := NewResponseWriter(rec, false, Config{})
+
+	if _, err := rw.Write([]byte("ok")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if got := rw.GetResponseCode(); got != http.StatusOK {
+		t.Fatalf("GetResponseCode() = %d, want %d (implicit WriteHeader)", got, http.StatusOK)
+	}
+}
+
+func TestResponseWriterTTFBAnchoredToConfigStartTime(t *testing.T) {
+	rec := httptest.NewRecorder()
+	start := time.Now().Add(-50 * time.Millisecond)
+	rw := NewResponseWriter(rec, false, Config{StartTime: start})
+
+	rw.WriteHeader(http.StatusOK)
+	ttfb := rw.GetTTFB()
+	if ttfb < 50*time.Millisecond {
+		t.Fatalf("GetTTFB() = %s, want at least 50ms since cfg.StartTime", ttfb)
+	}
+}
+
+func TestResponseWriterBodyTruncated(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rw := NewResponseWriter(rec, true, Config{MemBodyBytes: 4, MaxBodyBytes: 4})
+
+	if _, err := rw.Write([]byte("0123456789")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if !rw.GetBodyTruncated() {
+		t.Fatal("GetBodyTruncated() = false, want true")
+	}
+	if rec.Body.String() != "0123456789" {
+		t.Fatalf("underlying writer got %q, want the full untruncated body", rec.Body.String())
+	}
+}
+
+// hijackableRecorder adds a minimal http.Hijacker to httptest.ResponseRecorder
+// so Hijack() can be exercised without a real network connection.
+type hijackableRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+}
+
+func (h *hijackableRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h.hijacked = true
+	server, _ := net.Pipe()
+	return server, bufio.NewReadWriter(bufio.NewReader(server), bufio.NewWriter(server)), nil
+}
+
+func TestResponseWriterHijack(t *testing.T) {
+	rec := &hijackableRecorder{ResponseRecorder: httptest.NewRecorder()}
+	rw := NewResponseWriter(rec, false, Config{})
+
+	conn, _, err := rw.Hijack()
+	if err != nil {
+		t.Fatalf("Hijack: %v", err)
+	}
+	defer conn.Close()
+	if !rw.GetHijacked() {
+		t.Fatal("GetHijacked() = false, want true")
+	}
+}
+
+func TestResponseWriterHijackUnsupported(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rw := NewResponseWriter(rec, false, Config{})
+
+	if _, _, err := rw.Hijack(); err != ErrNotHijackable {
+		t.Fatalf("Hijack() error = %v, want ErrNotHijackable", err)
+	}
+}
+
+// readerFromRecorder implements io.ReaderFrom on top of httptest.ResponseRecorder
+// so the fast-path in ResponseWriter.ReadFrom can be exercised.
+type readerFromRecorder struct {
+	*httptest.ResponseRecorder
+}
+
+func (r *readerFromRecorder) ReadFrom(src io.Reader) (int64, error) {
+	return io.Copy(r.ResponseRecorder.Body, src)
+}
+
+func TestResponseWriterReadFromCountsBytes(t *testing.T) {
+	rec := &readerFromRecorder{ResponseRecorder: httptest.NewRecorder()}
+	rw := NewResponseWriter(rec, false, Config{})
+
+	src := newRepeatReader('x', 10)
+	n, err := rw.ReadFrom(src)
+	if err != nil {
+		t.Fatalf("ReadFrom: %v", err)
+	}
+	if n != 10 {
+		t.Fatalf("ReadFrom returned %d, want 10", n)
+	}
+	if got := rw.GetBytesWritten(); got != 10 {
+		t.Fatalf("GetBytesWritten() = %d, want 10 (must count the ReaderFrom fast-path)", got)
+	}
+}
+
+// repeatReader is a tiny bounded io.Reader, standing in for something like
+// os.File/http.ServeContent that would make *http.response take the
+// io.ReaderFrom fast-path.
+type repeatReader struct {
+	b byte
+	n int
+}
+
+func newRepeatReader(b byte, n int) *repeatReader {
+	return &repeatReader{b: b, n: n}
+}
+
+func (r *repeatReader) Read(p []byte) (int, error) {
+	if r.n <= 0 {
+		return 0, io.EOF
+	}
+	count := len(p)
+	if count > r.n {
+		count = r.n
+	}
+	for i := 0; i < count; i++ {
+		p[i] = r.b
+	}
+	r.n -= count
+	return count, nil
+}
@@ -1,10 +1,8 @@
 package httplog
 
 import (
-	"bytes"
 	"context"
 	"fmt"
-	"io"
 	"log/slog"
 	"net/http"
 	"time"
@@ -23,85 +21,151 @@ const (
 	ReqIDKeyName string = "request_id"
 )
 
+// bodyLogAttr builds the slog.Attr describing a captured body: the body
+// itself under key if it fits within maxRead, or a "skipped"/"error"
+// explanation otherwise.
+func bodyLogAttr(key string, size, maxRead int64, peek func(int64) ([]byte, error)) slog.Attr {
+	if size > maxRead {
+		return slog.String("skipped", fmt.Sprintf("body exceeds max debug size of %d", maxRead))
+	}
+	data, err := peek(size)
+	if err != nil {
+		return slog.String("error", fmt.Sprintf("failed to read captured body: %s", err))
+	}
+	return slog.String(key, string(data))
+}
+
 // Log is a HTTP middleware that logs HTTP requests and responses. Use it to decorates your actual http handlers.
-// Request body and response body are logged only if the wrapped slogger's level is set to LevelDebug or lower.
+// Request body and response body are logged if the wrapped slogger's level is set to LevelDebug or lower, or
+// if WithBodyLogOn was used and its predicate matches the response status code. The "HTTP request received" and
+// successful "HTTP request handled" lines are logged at [WithLevel]'s reqLevel and respLevel, Info by default.
 func (l *Logger) Log(next http.HandlerFunc) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
-		// Generate a uniq ID for this request
-		reqID := l.requests.Add(1)
+		// Generate a uniq ID for this request. TotalRequests counts every
+		// request regardless of l.reqIDGen, which only affects the ID used
+		// in log lines.
+		var reqID uint64
+		if l.reqIDGen != nil {
+			reqID = l.reqIDGen(r)
+			l.requests.Add(1)
+		} else {
+			reqID = l.requests.Add(1)
+		}
 		logger := l.slogger.With(slog.Uint64(ReqIDKeyName, reqID))
+		reqLevelEnabled := logger.Handler().Enabled(r.Context(), l.reqLevel)
+		debugEnabled := logger.Handler().Enabled(r.Context(), slog.LevelDebug)
 		// Log the request
-		if logger.Handler().Enabled(r.Context(), slog.LevelInfo) {
-			logger.InfoContext(r.Context(), "HTTP request received",
+		if reqLevelEnabled {
+			logger.Log(r.Context(), l.reqLevel, "HTTP request received",
 				slog.String("host", r.Host),
 				slog.String("method", r.Method),
 				slog.String("URI", r.URL.RequestURI()),
 				slog.String("client", r.RemoteAddr),
-				slog.Any("headers", r.Header),
+				slog.Any("headers", l.sanitizeHeader(r.Header)),
 			)
 		}
-		// If debug is on, try to log body up to a certain size
-		if r.ContentLength > 0 && logger.Handler().Enabled(r.Context(), slog.LevelDebug) {
-			var bodyAttribute slog.Attr
-			if r.ContentLength <= l.bodyMaxRead {
-				// Read body
-				var bodyBuffer bytes.Buffer
-				if _, err := io.CopyN(&bodyBuffer, r.Body, l.bodyMaxRead); err != nil && err != io.EOF {
-					slog.ErrorContext(r.Context(), "Failed to read body",
-						slog.String("host", r.Host),
-						slog.String("method", r.Method),
-						slog.String("URI", r.URL.RequestURI()),
-						slog.String("client", r.RemoteAddr),
-						slog.Any("headers", r.Header),
-						slog.String("error", err.Error()),
-					)
-					http.Error(
-						w,
-						fmt.Sprintf("%s: failed to read body: %s",
-							http.StatusText(http.StatusInternalServerError),
-							err.Error(),
-						),
-						http.StatusInternalServerError,
-					)
-					return
-				}
-				// Add body content to the future log
-				bodyAttribute = slog.String("body", bodyBuffer.String())
-				// Make the body available again
-				r.Body = io.NopCloser(&bodyBuffer)
-			} else {
-				bodyAttribute = slog.String("skipped", fmt.Sprintf("body exceeds max debug size of %d", l.bodyMaxRead))
-			}
-			logger.DebugContext(r.Context(), "HTTP request body",
-				bodyAttribute,
-			)
+		// Capture the body regardless of Content-Length (so chunked requests
+		// are covered too) whenever debug logging is on, or whenever
+		// WithBodyLogOn is configured (it needs the body available in case
+		// its predicate matches once the response is known, regardless of
+		// whether the "HTTP request received" line itself gets logged).
+		// Capture is a side channel tee on r.Body (see
+		// catcherflusher.NewCaptureReader): hitting MaxBodyBytes only truncates
+		// what gets logged later, it never rejects the request or changes what
+		// the wrapped handler reads, mirroring how response body capture
+		// already behaves.
+		captureBody := debugEnabled || l.bodyLogOn != nil
+		var reqBody *catcherflusher.BodyBuffer
+		if captureBody {
+			reqBody = catcherflusher.NewBodyBuffer(l.memBodyBytes, l.maxBodyBytes, l.bodyTempDir)
+			defer reqBody.Close()
+			r.Body = catcherflusher.NewCaptureReader(r.Body, reqBody)
 		}
 		// Pass to the wrapped handler
-		flusherCatcher := catcherflusher.NewResponseWriter(w, logger.Handler().Enabled(r.Context(), slog.LevelDebug))
+		flusherCatcher := catcherflusher.NewResponseWriter(
+			w, captureBody,
+			catcherflusher.Config{
+				MemBodyBytes:          l.memBodyBytes,
+				MaxBodyBytes:          l.maxBodyBytes,
+				BodyTempDir:           l.bodyTempDir,
+				StreamingContentTypes: l.streamingContentTypes,
+				StartTime:             start,
+			},
+		)
+		defer flusherCatcher.Close()
+		if l.beforeHook != nil {
+			l.beforeHook(r, flusherCatcher)
+		}
 		next.ServeHTTP(
 			flusherCatcher,
 			r.WithContext(context.WithValue(r.Context(), ReqIDKey, reqID)),
 		)
+		if l.afterHook != nil {
+			l.afterHook(r, flusherCatcher)
+		}
+		// If the connection was hijacked (e.g. WebSocket upgrade), the response
+		// code and body are meaningless: log the hijack and stop here.
+		if flusherCatcher.GetHijacked() {
+			logger.Log(r.Context(), l.respLevel, "HTTP connection hijacked",
+				slog.Duration("response_time", time.Since(start)),
+			)
+			return
+		}
 		// Log the response
-		logger.InfoContext(r.Context(), "HTTP request handled",
-			slog.Int("response_code", flusherCatcher.GetResponseCode()),
-			slog.String("response_status", http.StatusText(flusherCatcher.GetResponseCode())),
-			slog.Duration("response_time", time.Since(start)),
-		)
-		if logger.Handler().Enabled(r.Context(), slog.LevelDebug) {
-			body := flusherCatcher.GetBody()
-			if int64(len(body)) <= l.bodyMaxRead {
-				logger.DebugContext(r.Context(), "HTTP response",
-					slog.String("response_body", string(body)),
-					slog.Int("response_size", len(body)),
+		status := flusherCatcher.GetResponseCode()
+		totalTime := time.Since(start)
+		ttfb := flusherCatcher.GetTTFB()
+		respAttrs := []any{
+			slog.Int("response_code", status),
+			slog.String("response_status", http.StatusText(status)),
+			slog.Duration("response_time_total", totalTime),
+			slog.Duration("response_time_after_first_byte", totalTime-ttfb),
+			slog.Duration("ttfb", ttfb),
+			slog.Int64("response_bytes", flusherCatcher.GetBytesWritten()),
+			slog.Any("response_headers", l.sanitizeHeader(flusherCatcher.Header())),
+		}
+		if werr := flusherCatcher.GetWriteError(); werr != nil {
+			// The handler likely ignored this error: surface it here instead
+			// of silently dropping it.
+			respAttrs = append(respAttrs,
+				slog.String("write_error", flusherCatcher.WriteErrorSummary()),
+				slog.Int("write_error_count", flusherCatcher.GetWriteErrorCount()),
+			)
+			logger.ErrorContext(r.Context(), "HTTP request handled", respAttrs...)
+		} else {
+			logger.Log(r.Context(), l.respLevel, "HTTP request handled", respAttrs...)
+		}
+		if debugEnabled {
+			// Logged here, after the handler ran, rather than right after
+			// capture: the body is tee'd as the handler reads it (see
+			// NewCaptureReader above), so it is only fully known once the
+			// handler is done with it.
+			if reqBody != nil {
+				logger.DebugContext(r.Context(), "HTTP request body",
+					bodyLogAttr("body", reqBody.Size(), l.bodyMaxRead, reqBody.Peek),
+					slog.Bool("body_truncated", reqBody.Truncated()),
 				)
-			} else {
-				logger.DebugContext(r.Context(), "HTTP response",
-					slog.String("skipped", fmt.Sprintf("body exceeds max debug size of %d", l.bodyMaxRead)),
-					slog.Int("response_size", len(body)),
+			}
+			logger.DebugContext(r.Context(), "HTTP response",
+				bodyLogAttr("response_body", flusherCatcher.GetBodySize(), l.bodyMaxRead, flusherCatcher.PeekBody),
+				slog.Bool("response_body_truncated", flusherCatcher.GetBodyTruncated()),
+			)
+		} else if l.bodyLogOn != nil && l.bodyLogOn(status) {
+			level := slog.LevelWarn
+			if status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+			if reqBody != nil {
+				logger.Log(r.Context(), level, "HTTP request body",
+					bodyLogAttr("body", reqBody.Size(), l.bodyMaxRead, reqBody.Peek),
+					slog.Bool("body_truncated", reqBody.Truncated()),
 				)
 			}
+			logger.Log(r.Context(), level, "HTTP response",
+				bodyLogAttr("response_body", flusherCatcher.GetBodySize(), l.bodyMaxRead, flusherCatcher.PeekBody),
+				slog.Bool("response_body_truncated", flusherCatcher.GetBodyTruncated()),
+			)
 		}
 	})
 }
@@ -0,0 +1,198 @@
+package httplog
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hekmon/httplog/catcherflusher"
+)
+
+// discardLogger returns a Logger backed by a slog.Logger that throws away
+// every record, for tests that only care about the HTTP side effects.
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// recordingHandler captures every record it is given, so tests can assert on
+// what Logger.Log actually emitted.
+type recordingHandler struct {
+	mu      sync.Mutex
+	level   slog.Leveler
+	records []slog.Record
+}
+
+func newRecordingHandler(level slog.Leveler) *recordingHandler {
+	return &recordingHandler{level: level}
+}
+
+func (h *recordingHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *recordingHandler) Handle(_ context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, r)
+	return nil
+}
+
+func (h *recordingHandler) WithAttrs(_ []slog.Attr) slog.Handler { return h }
+func (h *recordingHandler) WithGroup(_ string) slog.Handler      { return h }
+
+func (h *recordingHandler) messages() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	msgs := make([]string, len(h.records))
+	for i, r := range h.records {
+		msgs[i] = r.Message
+	}
+	return msgs
+}
+
+func TestLogOversizedBodyWithBodyLogOnDoesNotReject(t *testing.T) {
+	handler := newRecordingHandler(slog.LevelInfo)
+	l := New(slog.New(handler), WithBodyLogOn(nil), WithMaxBodyBytes(4))
+
+	var gotBody string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatalf("handler: ReadAll: %v", err)
+		}
+		gotBody = string(b)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	srv := httptest.NewServer(l.Log(next))
+	defer srv.Close()
+
+	const body = "this body is way bigger than the configured max"
+	resp, err := http.Post(srv.URL, "text/plain", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d (capture must never reject the request)", resp.StatusCode, http.StatusOK)
+	}
+	if gotBody != body {
+		t.Fatalf("handler received %q, want the full untruncated body %q", gotBody, body)
+	}
+}
+
+func TestLogRequestIDGenerator(t *testing.T) {
+	handler := newRecordingHandler(slog.LevelInfo)
+	l := New(slog.New(handler), WithRequestIDGenerator(func(r *http.Request) uint64 {
+		return 42
+	}))
+
+	var sawID uint64
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sawID = r.Context().Value(ReqIDKey).(uint64)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	srv := httptest.NewServer(l.Log(next))
+	defer srv.Close()
+
+	if _, err := http.Get(srv.URL); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if sawID != 42 {
+		t.Fatalf("request ID = %d, want 42", sawID)
+	}
+	if got := l.TotalRequests(); got != 1 {
+		t.Fatalf("TotalRequests() = %d, want 1", got)
+	}
+}
+
+func TestLogBeforeAfterHooksRunAroundHandler(t *testing.T) {
+	var order []string
+	l := New(discardLogger(),
+		WithBeforeHook(func(r *http.Request, rw *catcherflusher.ResponseWriter) {
+			order = append(order, "before")
+		}),
+		WithAfterHook(func(r *http.Request, rw *catcherflusher.ResponseWriter) {
+			order = append(order, "after")
+		}),
+	)
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		order = append(order, "handler")
+		w.WriteHeader(http.StatusOK)
+	})
+
+	srv := httptest.NewServer(l.Log(next))
+	defer srv.Close()
+
+	if _, err := http.Get(srv.URL); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	want := []string{"before", "handler", "after"}
+	if len(order) != len(want) {
+		t.Fatalf("hook order = %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("hook order = %v, want %v", order, want)
+		}
+	}
+}
+
+func TestLogResponseStatusAndBytes(t *testing.T) {
+	handler := newRecordingHandler(slog.LevelInfo)
+	l := New(slog.New(handler))
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte("short and stout"))
+	})
+
+	srv := httptest.NewServer(l.Log(next))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusTeapot {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusTeapot)
+	}
+
+	msgs := handler.messages()
+	found := false
+	for _, m := range msgs {
+		if m == "HTTP request handled" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("messages = %v, want one of them to be %q", msgs, "HTTP request handled")
+	}
+
+	var respBytes int64 = -1
+	handler.mu.Lock()
+	for _, r := range handler.records {
+		if r.Message != "HTTP request handled" {
+			continue
+		}
+		r.Attrs(func(a slog.Attr) bool {
+			if a.Key == "response_bytes" {
+				respBytes = a.Value.Int64()
+			}
+			return true
+		})
+	}
+	handler.mu.Unlock()
+	if want := int64(len("short and stout")); respBytes != want {
+		t.Fatalf("response_bytes = %d, want %d", respBytes, want)
+	}
+}
@@ -3,35 +3,219 @@ package httplog
 import (
 	"context"
 	"log/slog"
+	"net/http"
 	"sync/atomic"
+
+	"github.com/hekmon/httplog/catcherflusher"
 )
 
 var (
 	// DefaultBodyMaxRead is the default maximum number of bytes a body must have to be logged.
 	// Its value is copied in the New() constructor.
 	DefaultBodyMaxRead int64 = 10000
-	// DefaultSanitizeHeaders is the default list of headers to sanitize in the debug log.
-	SanitizeHeaders = []string{"Authorization"}
+	// SanitizeHeaders is the default list of headers redacted in the log.
+	// Its value is copied in the New() constructor; use WithSanitizedHeaders
+	// to add to it on a per-Logger basis.
+	SanitizeHeaders = []string{"Authorization", "Cookie", "Set-Cookie"}
+	// DefaultMemBodyBytes is the default number of body bytes kept in memory
+	// before spilling to a temporary file. Its value is copied in the New()
+	// constructor.
+	DefaultMemBodyBytes int64 = 16 * 1024
+	// DefaultMaxBodyBytes is the default hard ceiling on how many body bytes
+	// are captured in total, in memory and on disk combined, before capture
+	// is truncated. This applies to request and response bodies alike: the
+	// request itself is never rejected, it reaches the wrapped handler in
+	// full. Its value is copied in the New() constructor.
+	DefaultMaxBodyBytes int64 = 10 * 1024 * 1024
+	// DefaultShouldLogBody is the predicate used by [WithBodyLogOn] when
+	// called with a nil function: it logs bodies for 4xx and 5xx responses.
+	DefaultShouldLogBody catcherflusher.ShouldLogBodyFunc = func(status int) bool {
+		return status >= http.StatusBadRequest
+	}
 )
 
 // Logger is a HTTP request/response logging utility.
 // It wraps a slog.Logger and provides additional functionality for logging HTTP requests and responses.
 // Instanciate with New().
 type Logger struct {
-	slogger     *slog.Logger
-	requests    atomic.Uint64
-	bodyMaxRead int64
+	slogger               *slog.Logger
+	requests              atomic.Uint64
+	bodyMaxRead           int64
+	memBodyBytes          int64
+	maxBodyBytes          int64
+	bodyTempDir           string
+	bodyLogOn             catcherflusher.ShouldLogBodyFunc
+	streamingContentTypes []string
+	reqIDGen              func(*http.Request) uint64
+	beforeHook            Hook
+	afterHook             Hook
+	reqLevel              slog.Level
+	respLevel             slog.Level
+
+	sanitizedHeaders map[string]struct{}
+	sanitizedMatcher func(name string) bool
+}
+
+// Option customizes a Logger created by [New].
+type Option func(*Logger)
+
+// Hook is called by [Logger.Log] with the in-flight request and the
+// [catcherflusher.ResponseWriter] wrapping its response, for side effects
+// such as metrics collection. See [WithBeforeHook] and [WithAfterHook].
+type Hook func(r *http.Request, rw *catcherflusher.ResponseWriter)
+
+// WithMemBodyBytes sets how many request/response body bytes a Logger keeps
+// in memory before spilling the capture to a temporary file. Defaults to
+// [DefaultMemBodyBytes].
+func WithMemBodyBytes(n int64) Option {
+	return func(l *Logger) {
+		l.memBodyBytes = n
+	}
+}
+
+// WithMaxBodyBytes sets the hard ceiling, in memory and on disk combined, on
+// how many request/response body bytes a Logger will capture. Once reached,
+// capture is truncated (the "body_truncated"/"response_body_truncated" log
+// attributes report it) without affecting the request or response itself:
+// capture is always a side channel tee, never a blocking read. Defaults to
+// [DefaultMaxBodyBytes].
+func WithMaxBodyBytes(n int64) Option {
+	return func(l *Logger) {
+		l.maxBodyBytes = n
+	}
+}
+
+// WithBodyTempDir sets the directory in which spilled body captures are
+// created with os.CreateTemp. Defaults to the empty string, which means
+// os.TempDir().
+func WithBodyTempDir(path string) Option {
+	return func(l *Logger) {
+		l.bodyTempDir = path
+	}
+}
+
+// WithBodyLogOn switches the Logger to an error-focused body logging mode:
+// request and response bodies are always captured (at Info level, without
+// requiring the debug level to be enabled), but only emitted to slog when fn
+// returns true for the final response status code, at Warn (4xx) or Error
+// (5xx and above) level. Passing a nil fn uses [DefaultShouldLogBody]. This
+// coexists with the existing debug-body logging: Debug level always logs
+// bodies regardless of fn.
+func WithBodyLogOn(fn catcherflusher.ShouldLogBodyFunc) Option {
+	if fn == nil {
+		fn = DefaultShouldLogBody
+	}
+	return func(l *Logger) {
+		l.bodyLogOn = fn
+	}
+}
+
+// WithSanitizedHeaders adds header names to the set of headers this Logger
+// redacts, on top of the [SanitizeHeaders] defaults. Matching is
+// case-insensitive.
+func WithSanitizedHeaders(names ...string) Option {
+	return func(l *Logger) {
+		for _, name := range canonicalHeaderNames(names) {
+			l.sanitizedHeaders[name] = struct{}{}
+		}
+	}
+}
+
+// WithSanitizedHeaderMatcher sets a predicate, called with a MIME-canonical
+// header name (e.g. "X-Api-Token"), that lets this Logger redact headers by
+// pattern (regex, glob, prefix/suffix, ...) in addition to the names set via
+// [SanitizeHeaders] and [WithSanitizedHeaders]. Calling it again replaces the
+// previous matcher.
+func WithSanitizedHeaderMatcher(matcher func(name string) bool) Option {
+	return func(l *Logger) {
+		l.sanitizedMatcher = matcher
+	}
+}
+
+// WithBodyMaxRead sets the maximum number of captured body bytes a Logger
+// will actually emit in a debug or error-triggered body log; bodies larger
+// than this are replaced by a "skipped" note instead. Defaults to
+// [DefaultBodyMaxRead].
+func WithBodyMaxRead(n int64) Option {
+	return func(l *Logger) {
+		l.bodyMaxRead = n
+	}
+}
+
+// WithStreamingContentTypes sets the response Content-Type values that make
+// this Logger's ResponseWriter auto-flush after every write, replacing
+// [catcherflusher.StreamingContentTypes]'s package-wide default for this
+// Logger only.
+func WithStreamingContentTypes(contentTypes ...string) Option {
+	if contentTypes == nil {
+		// a nil slice would fall back to the package-wide default
+		contentTypes = []string{}
+	}
+	return func(l *Logger) {
+		l.streamingContentTypes = contentTypes
+	}
+}
+
+// WithRequestIDGenerator overrides how each request's ID is derived. By
+// default an atomic counter is used; fn lets it be computed from the request
+// instead, e.g. echoing an incoming X-Request-Id header. fn only affects the
+// ID attached to log lines and returned by [GetReqIDSLogAttr]:
+// [Logger.TotalRequests] keeps counting every request regardless.
+func WithRequestIDGenerator(fn func(*http.Request) uint64) Option {
+	return func(l *Logger) {
+		l.reqIDGen = fn
+	}
+}
+
+// WithBeforeHook sets a [Hook] invoked right before the wrapped handler runs,
+// once the request's ResponseWriter has been created. Useful for starting a
+// metrics timer or similar bookkeeping tied to the ResponseWriter itself.
+func WithBeforeHook(fn Hook) Option {
+	return func(l *Logger) {
+		l.beforeHook = fn
+	}
+}
+
+// WithAfterHook sets a [Hook] invoked right after the wrapped handler
+// returns, before the response is logged. Useful for recording metrics such
+// as response code or size without duplicating [ResponseWriter]'s
+// bookkeeping.
+func WithAfterHook(fn Hook) Option {
+	return func(l *Logger) {
+		l.afterHook = fn
+	}
+}
+
+// WithLevel sets the slog level used for the "HTTP request received" log
+// (reqLevel) and for the successful "HTTP request handled" log (respLevel).
+// Both default to slog.LevelInfo. Responses logged because of a write error
+// or a [WithBodyLogOn] match keep their own fixed levels.
+func WithLevel(reqLevel, respLevel slog.Level) Option {
+	return func(l *Logger) {
+		l.reqLevel = reqLevel
+		l.respLevel = respLevel
+	}
 }
 
 // New creates a new HTTP request/response logging utility.
-func New(logger *slog.Logger) (l *Logger) {
+func New(logger *slog.Logger, opts ...Option) (l *Logger) {
 	if logger == nil {
 		return
 	}
-	return &Logger{
-		slogger:     logger,
-		bodyMaxRead: DefaultBodyMaxRead,
+	l = &Logger{
+		slogger:      logger,
+		bodyMaxRead:  DefaultBodyMaxRead,
+		memBodyBytes: DefaultMemBodyBytes,
+		maxBodyBytes: DefaultMaxBodyBytes,
+	}
+	l.sanitizedHeaders = make(map[string]struct{}, len(SanitizeHeaders))
+	for _, name := range canonicalHeaderNames(SanitizeHeaders) {
+		l.sanitizedHeaders[name] = struct{}{}
+	}
+	for _, opt := range opts {
+		opt(l)
 	}
+	return l
 }
 
 // TotalRequests returns the number of requests that went thru the logger.
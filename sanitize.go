@@ -0,0 +1,77 @@
+package httplog
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"strings"
+)
+
+// redactValue replaces a sensitive header value with a fixed marker that
+// still embeds a short hash of the original, so the same value can be
+// correlated across log lines without ever being written in clear.
+func redactValue(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return fmt.Sprintf("<redacted:sha256:%x>", sum[:4])
+}
+
+// shouldSanitize reports whether the given header name (as found in a
+// http.Header map, i.e. already MIME-canonicalized) must be redacted.
+func (l *Logger) shouldSanitize(name string) bool {
+	if _, ok := l.sanitizedHeaders[name]; ok {
+		return true
+	}
+	return l.sanitizedMatcher != nil && l.sanitizedMatcher(name)
+}
+
+// sanitizeHeader returns a redacted clone of h: never mutate h itself, it may
+// still be in use by the net/http stack. Set-Cookie is special-cased so only
+// the cookie value is redacted, keeping attributes such as Path or Expires
+// readable.
+func (l *Logger) sanitizeHeader(h http.Header) http.Header {
+	clone := h.Clone()
+	for name, values := range clone {
+		switch {
+		case name == "Set-Cookie":
+			redacted := make([]string, len(values))
+			for i, v := range values {
+				redacted[i] = sanitizeSetCookie(v)
+			}
+			clone[name] = redacted
+		case l.shouldSanitize(name):
+			redacted := make([]string, len(values))
+			for i, v := range values {
+				redacted[i] = redactValue(v)
+			}
+			clone[name] = redacted
+		}
+	}
+	return clone
+}
+
+// sanitizeSetCookie redacts only the value of a Set-Cookie header line,
+// leaving its name and attributes (Path, Domain, Expires, ...) untouched.
+func sanitizeSetCookie(line string) string {
+	nameValue, attrs, hasAttrs := strings.Cut(line, ";")
+	name, value, hasValue := strings.Cut(nameValue, "=")
+	if !hasValue {
+		// malformed cookie, nothing safe to redact selectively
+		return redactValue(line)
+	}
+	redacted := name + "=" + redactValue(value)
+	if hasAttrs {
+		redacted += ";" + attrs
+	}
+	return redacted
+}
+
+// canonicalHeaderNames canonicalizes each name as textproto/http.Header does,
+// so later comparisons against http.Header keys succeed regardless of case.
+func canonicalHeaderNames(names []string) []string {
+	canon := make([]string, len(names))
+	for i, name := range names {
+		canon[i] = textproto.CanonicalMIMEHeaderKey(name)
+	}
+	return canon
+}
@@ -0,0 +1,65 @@
+package httplog
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeHeaderRedactsDefaultsAndCustom(t *testing.T) {
+	l := New(discardLogger(), WithSanitizedHeaders("X-Api-Key"))
+
+	h := http.Header{}
+	h.Set("Authorization", "Bearer secret")
+	h.Set("X-Api-Key", "abc123")
+	h.Set("X-Request-Id", "keep-me")
+
+	out := l.sanitizeHeader(h)
+	if out.Get("X-Request-Id") != "keep-me" {
+		t.Fatalf("untouched header was modified: %q", out.Get("X-Request-Id"))
+	}
+	for _, name := range []string{"Authorization", "X-Api-Key"} {
+		got := out.Get(name)
+		if !strings.HasPrefix(got, "<redacted:sha256:") {
+			t.Fatalf("%s = %q, want a redacted marker", name, got)
+		}
+	}
+	// the original header must never be mutated
+	if h.Get("Authorization") != "Bearer secret" {
+		t.Fatalf("original header was mutated: %q", h.Get("Authorization"))
+	}
+}
+
+func TestSanitizeHeaderSetCookieKeepsAttributes(t *testing.T) {
+	l := New(discardLogger())
+
+	h := http.Header{}
+	h.Set("Set-Cookie", "session=abc123; Path=/; HttpOnly")
+
+	out := l.sanitizeHeader(h)
+	got := out.Get("Set-Cookie")
+	if !strings.HasPrefix(got, "session=<redacted:sha256:") {
+		t.Fatalf("Set-Cookie = %q, want redacted value with name kept", got)
+	}
+	if !strings.HasSuffix(got, "; Path=/; HttpOnly") {
+		t.Fatalf("Set-Cookie = %q, want attributes preserved", got)
+	}
+}
+
+func TestSanitizeHeaderMatcher(t *testing.T) {
+	l := New(discardLogger(), WithSanitizedHeaderMatcher(func(name string) bool {
+		return strings.HasPrefix(name, "X-Secret-")
+	}))
+
+	h := http.Header{}
+	h.Set("X-Secret-Token", "shh")
+	h.Set("X-Public-Info", "fine")
+
+	out := l.sanitizeHeader(h)
+	if out.Get("X-Public-Info") != "fine" {
+		t.Fatalf("non-matching header was redacted: %q", out.Get("X-Public-Info"))
+	}
+	if strings.Contains(out.Get("X-Secret-Token"), "shh") {
+		t.Fatal("matcher-selected header was not redacted")
+	}
+}